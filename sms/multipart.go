@@ -0,0 +1,278 @@
+package sms
+
+import (
+	"bytes"
+	"sort"
+	"unicode/utf16"
+)
+
+// Concatenation IEIs as described in 3GPP TS 23.040 §9.2.3.24.1.
+const (
+	concatIEI8Bit  = 0x00 // concatenated short messages, 8-bit reference number
+	concatIEI16Bit = 0x08 // concatenated short messages, 16-bit reference number
+)
+
+// Per-segment payload budgets for a concatenated message. The TPDU user
+// data capacity is 140 octets. An 8-bit-reference concatenation UDH
+// occupies 6 of them (1 UDH length octet + 1 IEI + 1 IE length + 3 IE
+// data octets); a 16-bit-reference one occupies 7 (one extra IE data
+// octet for the wider reference). For GSM 7-bit, those header octets,
+// padded to the next septet boundary (one fill bit after 6 octets, none
+// after 7), consume the equivalent of 7 or 8 septets, leaving 153 or 152
+// septets of text. For UCS2, the remaining 134 or 133 octets hold 67
+// or 66 UTF-16 code units.
+const (
+	concatPartSeptets8Bit  = 153
+	concatPartSeptets16Bit = 152
+	concatPartChars8Bit    = 67
+	concatPartChars16Bit   = 66
+)
+
+// Split breaks a long message into segments suitable for transfer as a
+// concatenated short message, each carrying a UserDataHeader concatenation
+// IE (IEI=0x00 with an 8-bit reference, or IEI=0x08 with a 16-bit
+// reference when ref doesn't fit in 8 bits) as described in 3GPP TS
+// 23.040 §9.2.3.24.1. A message needing more than 255 segments is
+// rejected with ErrIncorrectSize. The segments share every other
+// field of s; only Text and the UDH differ. s itself is left unmodified.
+// For GSM 7-bit, segments are budgeted in septets (a GSM 03.38
+// extension-table character costs 2) rather than runes; for UCS2, they are
+// cut on UTF-16 code unit boundaries. Neither an extension-table escape
+// pair nor a UTF-16 surrogate pair is ever split across two parts.
+func (s *Message) Split(ref uint16) ([]*Message, error) {
+	var runes []rune
+	var units []uint16
+	var partSize8Bit, partSize16Bit int
+	switch s.Encoding {
+	case Encodings.Gsm7Bit, Encodings.Gsm7Bit_2:
+		runes = []rune(s.Text)
+		partSize8Bit, partSize16Bit = concatPartSeptets8Bit, concatPartSeptets16Bit
+	case Encodings.UCS2:
+		units = utf16.Encode([]rune(s.Text))
+		partSize8Bit, partSize16Bit = concatPartChars8Bit, concatPartChars16Bit
+	default:
+		return nil, ErrUnknownEncoding
+	}
+
+	var bounds []unitRange
+	if units != nil {
+		bounds = splitUCS2Bounds(units, partSize8Bit)
+	} else {
+		bounds = splitGsm7Bounds(runes, partSize8Bit)
+	}
+	use16Bit := ref > 0xFF || len(bounds) > 0xFF
+	if use16Bit {
+		if units != nil {
+			bounds = splitUCS2Bounds(units, partSize16Bit)
+		} else {
+			bounds = splitGsm7Bounds(runes, partSize16Bit)
+		}
+	}
+	total := len(bounds)
+	// total and seq are single TP-UDH octets regardless of reference width.
+	if total > 0xFF {
+		return nil, ErrIncorrectSize
+	}
+
+	parts := make([]*Message, 0, total)
+	for i, b := range bounds {
+		udh, err := concatHeader(ref, byte(total), byte(i+1), use16Bit)
+		if err != nil {
+			return nil, err
+		}
+
+		part := *s
+		if units != nil {
+			part.Text = string(utf16.Decode(units[b.start:b.end]))
+		} else {
+			part.Text = string(runes[b.start:b.end])
+		}
+		part.UserDataStartsWithHeader = true
+		part.UserDataHeader = udh
+		parts = append(parts, &part)
+	}
+	return parts, nil
+}
+
+type unitRange struct{ start, end int }
+
+// splitUCS2Bounds divides units, UTF-16 code units, into segments of at
+// most partSize units each, never placing a boundary between a surrogate
+// pair's two halves.
+func splitUCS2Bounds(units []uint16, partSize int) []unitRange {
+	if len(units) == 0 {
+		return []unitRange{{0, 0}}
+	}
+	var bounds []unitRange
+	for start := 0; start < len(units); {
+		end := start + partSize
+		if end > len(units) {
+			end = len(units)
+		}
+		if end < len(units) && isHighSurrogate(units[end-1]) {
+			end--
+		}
+		bounds = append(bounds, unitRange{start, end})
+		start = end
+	}
+	return bounds
+}
+
+// splitGsm7Bounds divides runes into segments costing at most partSize GSM
+// 7-bit septets each, as counted by septetCount: a rune from the GSM 03.38
+// extension table costs 2 septets and is never split from the escape
+// septet that precedes it.
+func splitGsm7Bounds(runes []rune, partSize int) []unitRange {
+	if len(runes) == 0 {
+		return []unitRange{{0, 0}}
+	}
+	var bounds []unitRange
+	start, septets := 0, 0
+	for i, r := range runes {
+		w := 1
+		if gsm7BitExtChars[r] {
+			w = 2
+		}
+		if septets+w > partSize && i > start {
+			bounds = append(bounds, unitRange{start, i})
+			start, septets = i, 0
+		}
+		septets += w
+	}
+	bounds = append(bounds, unitRange{start, len(runes)})
+	return bounds
+}
+
+func isHighSurrogate(u uint16) bool {
+	return u >= 0xD800 && u <= 0xDBFF
+}
+
+// Reassemble groups parts by (Address, reference) - all parts passed in
+// are expected to belong to a single such group - verifies the total and
+// seq fields carried in each part's concatenation header, sorts them by
+// seq, and returns a single Message with the concatenated Text. Parts may
+// be passed in any order.
+func Reassemble(parts []*Message) (*Message, error) {
+	if len(parts) == 0 {
+		return nil, ErrIncorrectSize
+	}
+
+	ref, _, total, ok := concatInfo(parts[0].UserDataHeader)
+	if !ok {
+		return nil, ErrIncorrectUserDataHeaderLength
+	}
+
+	bySeq := make(map[byte]*Message, len(parts))
+	for _, p := range parts {
+		partRef, seq, partTotal, ok := concatInfo(p.UserDataHeader)
+		if !ok || seq < 1 || seq > total || partRef != ref || partTotal != total || p.Address != parts[0].Address {
+			return nil, ErrIncorrectUserDataHeaderLength
+		}
+		bySeq[seq] = p
+	}
+	if len(bySeq) != int(total) {
+		return nil, ErrIncorrectSize
+	}
+
+	seqs := make([]byte, 0, len(bySeq))
+	for seq := range bySeq {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+
+	full := *parts[0]
+	full.UserDataHeader = UserDataHeader{}
+	full.UserDataStartsWithHeader = false
+	var text bytes.Buffer
+	for _, seq := range seqs {
+		text.WriteString(bySeq[seq].Text)
+	}
+	full.Text = text.String()
+	return &full, nil
+}
+
+// Reassembler buffers incoming concatenated segments so callers can opt in
+// to automatic reassembly instead of handling individual segments
+// themselves.
+type Reassembler struct {
+	pending map[reassemblyKey][]*Message
+}
+
+type reassemblyKey struct {
+	Address   PhoneNumber
+	Reference uint16
+}
+
+// NewReassembler creates an empty Reassembler.
+func NewReassembler() *Reassembler {
+	return &Reassembler{pending: make(map[reassemblyKey][]*Message)}
+}
+
+// Add buffers msg. Once every segment sharing msg's (Address, reference)
+// has been seen, Add returns the reassembled Message and ok=true, and
+// forgets the buffered segments. A msg that carries no concatenation
+// header is returned as-is with ok=true. Add returns ok=false while parts
+// are still outstanding.
+func (r *Reassembler) Add(msg *Message) (*Message, bool, error) {
+	ref, _, total, ok := concatInfo(msg.UserDataHeader)
+	if !ok {
+		return msg, true, nil
+	}
+
+	key := reassemblyKey{Address: msg.Address, Reference: ref}
+	r.pending[key] = append(r.pending[key], msg)
+	if len(r.pending[key]) < int(total) {
+		return nil, false, nil
+	}
+
+	parts := r.pending[key]
+	delete(r.pending, key)
+	full, err := Reassemble(parts)
+	if err != nil {
+		return nil, false, err
+	}
+	return full, true, nil
+}
+
+// concatHeader builds the UserDataHeader for a single concatenation IE.
+func concatHeader(ref uint16, total, seq byte, use16Bit bool) (UserDataHeader, error) {
+	var ie []byte
+	if use16Bit {
+		ie = []byte{concatIEI16Bit, 0x04, byte(ref >> 8), byte(ref), total, seq}
+	} else {
+		ie = []byte{concatIEI8Bit, 0x03, byte(ref), total, seq}
+	}
+	raw := append([]byte{byte(len(ie))}, ie...)
+
+	var udh UserDataHeader
+	if err := udh.ReadFrom(raw); err != nil {
+		return UserDataHeader{}, err
+	}
+	return udh, nil
+}
+
+// concatInfo extracts a concatenation IE's reference, sequence number and
+// total segment count from udh, if present.
+func concatInfo(udh UserDataHeader) (ref uint16, seq, total byte, ok bool) {
+	raw := udh.Bytes()
+	for i := 1; i+1 < len(raw); {
+		iei := raw[i]
+		ieLen := int(raw[i+1])
+		if i+2+ieLen > len(raw) {
+			return 0, 0, 0, false
+		}
+		data := raw[i+2 : i+2+ieLen]
+		switch iei {
+		case concatIEI8Bit:
+			if len(data) == 3 {
+				return uint16(data[0]), data[2], data[1], true
+			}
+		case concatIEI16Bit:
+			if len(data) == 4 {
+				return uint16(data[0])<<8 | uint16(data[1]), data[3], data[2], true
+			}
+		}
+		i += 2 + ieLen
+	}
+	return 0, 0, 0, false
+}
@@ -0,0 +1,106 @@
+package sms
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitReassembleGsm7Bit(t *testing.T) {
+	msg := &Message{
+		Type:     MessageTypes.Submit,
+		Encoding: Encodings.Gsm7Bit,
+		Address:  PhoneNumber("15551234567"),
+		Text:     strings.Repeat("a", concatPartSeptets8Bit*3+10),
+	}
+
+	parts, err := msg.Split(1)
+	if err != nil {
+		t.Fatalf("Split: %v", err)
+	}
+	if len(parts) != 4 {
+		t.Fatalf("got %d parts, want 4", len(parts))
+	}
+	for _, p := range parts {
+		if len(p.Text) > concatPartSeptets8Bit {
+			t.Errorf("part exceeds 8-bit UDH budget: %d septets", len(p.Text))
+		}
+	}
+
+	full, err := Reassemble(parts)
+	if err != nil {
+		t.Fatalf("Reassemble: %v", err)
+	}
+	if full.Text != msg.Text {
+		t.Errorf("reassembled text mismatch: got %d chars, want %d", len(full.Text), len(msg.Text))
+	}
+}
+
+func TestSplitUses16BitReferenceAboveByteRange(t *testing.T) {
+	msg := &Message{
+		Encoding: Encodings.Gsm7Bit,
+		Text:     strings.Repeat("a", concatPartSeptets16Bit+1),
+	}
+
+	parts, err := msg.Split(0x100)
+	if err != nil {
+		t.Fatalf("Split: %v", err)
+	}
+	for _, p := range parts {
+		raw := p.UserDataHeader.Bytes()
+		if len(raw) < 2 || raw[1] != concatIEI16Bit {
+			t.Fatalf("expected a 16-bit concatenation IE, got % x", raw)
+		}
+		if len(p.Text) > concatPartSeptets16Bit {
+			t.Errorf("part exceeds 16-bit UDH budget: %d septets", len(p.Text))
+		}
+	}
+}
+
+func TestSplitUCS2DoesNotCutSurrogatePair(t *testing.T) {
+	// U+1F600 (GRINNING FACE) encodes as a UTF-16 surrogate pair; repeat it
+	// so a naive rune-counted budget straddles the boundary differently
+	// than a UTF-16-unit-counted one.
+	const face = "\U0001F600"
+	msg := &Message{
+		Encoding: Encodings.UCS2,
+		Address:  PhoneNumber("15551234567"),
+		Text:     strings.Repeat(face, concatPartChars8Bit+5),
+	}
+
+	parts, err := msg.Split(1)
+	if err != nil {
+		t.Fatalf("Split: %v", err)
+	}
+	for _, p := range parts {
+		if !strings.HasSuffix(p.Text, face) && len(p.Text)%len(face) != 0 {
+			t.Errorf("part ends mid surrogate pair: %q", p.Text)
+		}
+	}
+
+	full, err := Reassemble(parts)
+	if err != nil {
+		t.Fatalf("Reassemble: %v", err)
+	}
+	if full.Text != msg.Text {
+		t.Errorf("reassembled text mismatch: got %d runes, want %d", len([]rune(full.Text)), len([]rune(msg.Text)))
+	}
+}
+
+func TestReassembleRejectsMixedReferences(t *testing.T) {
+	msg := &Message{
+		Encoding: Encodings.Gsm7Bit,
+		Text:     strings.Repeat("a", concatPartSeptets8Bit+1),
+	}
+	parts, err := msg.Split(1)
+	if err != nil {
+		t.Fatalf("Split: %v", err)
+	}
+	other, err := msg.Split(2)
+	if err != nil {
+		t.Fatalf("Split: %v", err)
+	}
+	mixed := []*Message{parts[0], other[1]}
+	if _, err := Reassemble(mixed); err == nil {
+		t.Error("Reassemble with mismatched references: want error, got nil")
+	}
+}
@@ -0,0 +1,263 @@
+// Package nbf decodes Nokia NBF phone-backup archives into sms.Message
+// values, so SMS history can be imported without talking to a modem.
+//
+// An NBF archive is a ZIP container. SMS/MMS entries live under
+// predefmessages/1 (inbox) and predefmessages/3 (outbox); every entry's
+// filename packs its metadata (sequence, timestamp, multipart sequence,
+// flags, part number/total, peer MSISDN and a checksum) and its body
+// holds a TPDU-like payload followed by a UTF-16BE text section.
+package nbf
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf16"
+
+	"github.com/xlab/at/sms"
+)
+
+// Directories inside an NBF archive that hold predefined (i.e. actually
+// stored) message entries.
+const (
+	inboxDir  = "predefmessages/1"
+	outboxDir = "predefmessages/3"
+)
+
+// Flags embedded in an entry's metadata filename.
+const (
+	flagSMS = 0x20
+	flagMMS = 0x10
+)
+
+// metaHeaderLen is the length, in bytes, of the hex-encoded binary part of
+// an entry's metadata filename, not counting the trailing MSISDN and
+// checksum.
+const metaHeaderLen = 15
+
+// Common errors.
+var (
+	ErrNotNBF         = errors.New("nbf: not a valid NBF archive")
+	ErrBadMetadata    = errors.New("nbf: malformed entry metadata")
+	ErrIncompletePart = errors.New("nbf: multipart message is missing a part")
+)
+
+var dosEpoch = time.Date(1980, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// entry is a single predefmessages file, decoded from its filename and
+// body.
+type entry struct {
+	Sequence     uint32
+	Timestamp    time.Time
+	MultipartSeq uint16
+	Flags        byte
+	PartNum      byte
+	PartTotal    byte
+	MSISDN       string
+	Checksum     byte
+	Payload      []byte
+}
+
+// Read decodes every SMS entry found in the NBF archive r and returns them
+// as sms.Message values, reassembling entries that the phone split into
+// several parts (grouped by MultipartSeq). Entries with PartTotal <= 1
+// aren't part of any multipart group, so each is kept in a group of its
+// own, keyed by its unique Sequence rather than the shared MultipartSeq
+// (which phones commonly leave at 0 for every single-part message).
+func Read(r io.ReaderAt, size int64) ([]*sms.Message, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, ErrNotNBF
+	}
+
+	type group struct {
+		minSeq uint32
+		parts  []entry
+	}
+	groups := make(map[interface{}]*group)
+
+	for _, f := range zr.File {
+		dir := path.Dir(f.Name)
+		if dir != inboxDir && dir != outboxDir {
+			continue
+		}
+		e, err := parseMetadata(path.Base(f.Name))
+		if err != nil || e.Flags&flagSMS == 0 {
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		e.Payload, err = io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		var key interface{} = e.MultipartSeq
+		if e.PartTotal <= 1 {
+			key = e.Sequence
+		}
+		g, ok := groups[key]
+		if !ok {
+			g = &group{minSeq: e.Sequence}
+			groups[key] = g
+		}
+		if e.Sequence < g.minSeq {
+			g.minSeq = e.Sequence
+		}
+		g.parts = append(g.parts, e)
+	}
+
+	ordered := make([]*group, 0, len(groups))
+	for _, g := range groups {
+		ordered = append(ordered, g)
+	}
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i].minSeq < ordered[j].minSeq })
+
+	msgs := make([]*sms.Message, 0, len(ordered))
+	for _, g := range ordered {
+		sort.Slice(g.parts, func(i, j int) bool { return g.parts[i].PartNum < g.parts[j].PartNum })
+		msg, err := assemble(g.parts)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+// assemble decodes a (possibly multipart) entry group into a single
+// sms.Message, filling in the fields NBF stores out-of-band (Address,
+// ServiceCenterTime) alongside the TPDU-derived ones.
+func assemble(parts []entry) (*sms.Message, error) {
+	if len(parts) == 0 {
+		return nil, ErrIncompletePart
+	}
+	first := parts[0]
+	if first.PartTotal > 0 && len(parts) != int(first.PartTotal) {
+		return nil, ErrIncompletePart
+	}
+
+	msg := new(sms.Message)
+	var text strings.Builder
+	for i, p := range parts {
+		tpdu, utf16Text, err := splitPayload(p.Payload)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			if _, err := msg.ReadFrom(tpdu); err != nil {
+				// Not every NBF payload is a well-formed TPDU; fall back
+				// to the fields NBF stores out-of-band.
+				*msg = sms.Message{}
+			}
+		}
+		text.WriteString(utf16BEToString(utf16Text))
+	}
+
+	msg.Address = sms.PhoneNumber(first.MSISDN)
+	msg.ServiceCenterTime.ReadFrom(timestampOctets(first.Timestamp))
+	msg.Text = text.String()
+	return msg, nil
+}
+
+// splitPayload separates an entry's body into its TPDU-like payload,
+// stored behind a 2-octet big-endian length prefix, and its UTF-16BE text
+// section, which takes up the rest of the body.
+func splitPayload(payload []byte) (tpdu, utf16Text []byte, err error) {
+	if len(payload) < 2 {
+		return nil, nil, ErrBadMetadata
+	}
+	tpduLen := int(binary.BigEndian.Uint16(payload[:2]))
+	if 2+tpduLen > len(payload) {
+		return nil, nil, ErrBadMetadata
+	}
+	return payload[2 : 2+tpduLen], payload[2+tpduLen:], nil
+}
+
+// parseMetadata decodes an entry's filename: a hex-encoded binary header
+// (sequence uint32, DOS timestamp uint32, multipart sequence uint16,
+// flags byte, and a packed part-number/part-total uint32), followed by a
+// zero-padded 12-digit peer MSISDN, a trailing checksum byte, and a
+// variable-length tail this package otherwise ignores.
+func parseMetadata(name string) (entry, error) {
+	if len(name) < metaHeaderLen*2+12+2 {
+		return entry{}, ErrBadMetadata
+	}
+	header, err := hex.DecodeString(name[:metaHeaderLen*2])
+	if err != nil {
+		return entry{}, ErrBadMetadata
+	}
+	msisdn := name[metaHeaderLen*2 : metaHeaderLen*2+12]
+	if strings.IndexFunc(msisdn, func(r rune) bool { return r < '0' || r > '9' }) != -1 {
+		return entry{}, ErrBadMetadata
+	}
+	checksum, err := hex.DecodeString(name[metaHeaderLen*2+12 : metaHeaderLen*2+12+2])
+	if err != nil {
+		return entry{}, ErrBadMetadata
+	}
+
+	packed := binary.BigEndian.Uint32(header[11:15])
+	e := entry{
+		Sequence:     binary.BigEndian.Uint32(header[0:4]),
+		Timestamp:    dosEpoch.Add(time.Duration(binary.BigEndian.Uint32(header[4:8])) * time.Second),
+		MultipartSeq: binary.BigEndian.Uint16(header[8:10]),
+		Flags:        header[10],
+		PartNum:      byte((packed >> 12) & 0xF),
+		PartTotal:    byte((packed >> 20) & 0xF),
+		Checksum:     checksum[0],
+		MSISDN:       strings.TrimLeft(msisdn, "0"),
+	}
+	if e.MSISDN == "" {
+		e.MSISDN = "0"
+	}
+	return e, nil
+}
+
+// timestampOctets encodes t as the 7-octet semi-octet timestamp described
+// in 3GPP TS 23.040 §9.2.3.11, the same layout sms.Timestamp parses.
+func timestampOctets(t time.Time) []byte {
+	_, offset := t.Zone()
+	quarters := offset / (15 * 60)
+	neg := quarters < 0
+	if neg {
+		quarters = -quarters
+	}
+	tz := semiOctet(quarters)
+	if neg {
+		tz |= 0x08
+	}
+	return []byte{
+		semiOctet(t.Year() % 100),
+		semiOctet(int(t.Month())),
+		semiOctet(t.Day()),
+		semiOctet(t.Hour()),
+		semiOctet(t.Minute()),
+		semiOctet(t.Second()),
+		tz,
+	}
+}
+
+func semiOctet(v int) byte {
+	return byte(v%10<<4 | v/10)
+}
+
+func utf16BEToString(b []byte) string {
+	if len(b)%2 != 0 {
+		b = b[:len(b)-1]
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
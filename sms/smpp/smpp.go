@@ -0,0 +1,429 @@
+// Package smpp provides conversion helpers between sms.Message and SMPP
+// 3.4 PDUs, as used by SMSC gateways, so the same Message value can drive
+// either an AT-command modem or an SMPP connection.
+package smpp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xlab/at/pdu"
+	"github.com/xlab/at/sms"
+)
+
+// TON values this package maps to/from sms.PhoneNumber.Type.
+const (
+	TONUnknown       = 0x00
+	TONInternational = 0x01
+	TONNational      = 0x02
+	TONAlphanumeric  = 0x05
+)
+
+// NPI values this package assigns; only ISDN/E.164 numbering is handled.
+const (
+	NPIUnknown = 0x00
+	NPIISDN    = 0x01
+)
+
+// data_coding values this package knows. GSM 7-bit and UCS2 map to/from
+// sms.Encoding; DataCodingLatin1 has no sms.Encoding counterpart and is
+// rejected with ErrUnsupportedEncoding.
+const (
+	DataCodingGSM7   = 0x00
+	DataCodingLatin1 = 0x03
+	DataCodingUCS2   = 0x08
+)
+
+// Optional TLV tags used for the SAR (segmentation and reassembly) group.
+const (
+	TagSarMsgRefNum     = 0x020C
+	TagSarTotalSegments = 0x020E
+	TagSarSegmentSeqnum = 0x020F
+)
+
+// esmClassUDHI marks esm_class bit 0x40: the short message starts with a
+// user data header.
+const esmClassUDHI = 0x40
+
+// Common errors.
+var ErrUnsupportedEncoding = errors.New("smpp: unsupported data_coding value")
+
+// Address is an SMPP source/destination address triple.
+type Address struct {
+	TON    byte
+	NPI    byte
+	Number string
+}
+
+// TLV is a single SMPP optional parameter.
+type TLV struct {
+	Tag   uint16
+	Value []byte
+}
+
+// SubmitSM is the subset of an SMPP submit_sm PDU this package maps
+// to/from sms.Message.
+type SubmitSM struct {
+	SourceAddr         Address
+	DestAddr           Address
+	EsmClass           byte
+	DataCoding         byte
+	ValidityPeriod     string
+	RegisteredDelivery byte
+	ShortMessage       []byte
+	TLVs               []TLV
+}
+
+// DeliverSM is the subset of an SMPP deliver_sm PDU this package maps
+// to/from sms.Message.
+type DeliverSM struct {
+	SourceAddr   Address
+	DestAddr     Address
+	EsmClass     byte
+	DataCoding   byte
+	ShortMessage []byte
+	TLVs         []TLV
+}
+
+// ToSubmitSM converts m into an SMPP submit_sm PDU. Concatenation is
+// carried as sar_msg_ref_num/sar_total_segments/sar_segment_seqnum TLVs
+// when m.UserDataHeader holds a concatenation IE; otherwise the UDH, if
+// any, stays inline in ShortMessage and EsmClass gets the UDHI bit set.
+func ToSubmitSM(m *sms.Message) (*SubmitSM, error) {
+	dc, err := dataCoding(m.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := shortMessage(m)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &SubmitSM{
+		DestAddr:           address(m.Address),
+		DataCoding:         dc,
+		RegisteredDelivery: registeredDelivery(m.StatusReportRequest),
+		ValidityPeriod:     validityPeriodString(m.VP, m.VPFormat),
+	}
+
+	if ref, seq, total, ok := concatIEs(m.UserDataHeader); ok {
+		p.TLVs = append(p.TLVs,
+			TLV{Tag: TagSarMsgRefNum, Value: []byte{byte(ref >> 8), byte(ref)}},
+			TLV{Tag: TagSarTotalSegments, Value: []byte{total}},
+			TLV{Tag: TagSarSegmentSeqnum, Value: []byte{seq}},
+		)
+		p.ShortMessage = payload
+	} else if m.UserDataStartsWithHeader {
+		p.EsmClass |= esmClassUDHI
+		header := m.UserDataHeader.Bytes()
+		if m.Encoding == sms.Encodings.Gsm7Bit || m.Encoding == sms.Encodings.Gsm7Bit_2 {
+			payload = packGsm7AfterHeader(m.Text, len(header))
+		}
+		p.ShortMessage = append(header, payload...)
+	} else {
+		p.ShortMessage = payload
+	}
+	return p, nil
+}
+
+// FromDeliverSM converts an SMPP deliver_sm PDU into an sms.Message,
+// reconstructing Address, Encoding and, when the SAR TLVs are present, the
+// UserDataHeader concatenation IE the AT-command side expects.
+func FromDeliverSM(p *DeliverSM) (*sms.Message, error) {
+	enc, err := encodingFromDataCoding(p.DataCoding)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &sms.Message{
+		Type:     sms.MessageTypes.Deliver,
+		Encoding: enc,
+		Address:  sms.PhoneNumber(p.SourceAddr.Number),
+	}
+
+	payload := p.ShortMessage
+	// udhInline tracks whether payload still has a UDH physically in front
+	// of it: true for the esm_class UDHI case, false for the SAR TLV case,
+	// where the concatenation info travels out-of-band and payload is pure
+	// text. Only the inline case needs pdu.DecodeUcs2 to skip a header.
+	var udhInline bool
+	if ref, total, seq, ok := sarTLVs(p.TLVs); ok {
+		m.UserDataStartsWithHeader = true
+		if err := m.UserDataHeader.ReadFrom(concatHeaderBytes(ref, total, seq)); err != nil {
+			return nil, err
+		}
+	} else if p.EsmClass&esmClassUDHI != 0 {
+		if len(payload) < 1 {
+			return nil, sms.ErrIncorrectUserDataHeaderLength
+		}
+		udhLen := int(payload[0])
+		if 1+udhLen > len(payload) {
+			return nil, sms.ErrIncorrectUserDataHeaderLength
+		}
+		m.UserDataStartsWithHeader = true
+		udhInline = true
+		if err := m.UserDataHeader.ReadFrom(payload[:1+udhLen]); err != nil {
+			return nil, err
+		}
+		payload = payload[1+udhLen:]
+	}
+
+	switch enc {
+	case sms.Encodings.Gsm7Bit, sms.Encodings.Gsm7Bit_2:
+		m.Text, err = pdu.Decode7Bit(payload)
+	case sms.Encodings.UCS2:
+		m.Text, err = pdu.DecodeUcs2(payload, udhInline)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func shortMessage(m *sms.Message) ([]byte, error) {
+	switch m.Encoding {
+	case sms.Encodings.Gsm7Bit, sms.Encodings.Gsm7Bit_2:
+		return pdu.Encode7Bit(m.Text), nil
+	case sms.Encodings.UCS2:
+		return pdu.EncodeUcs2(m.Text), nil
+	default:
+		return nil, ErrUnsupportedEncoding
+	}
+}
+
+func dataCoding(enc sms.Encoding) (byte, error) {
+	switch enc {
+	case sms.Encodings.Gsm7Bit, sms.Encodings.Gsm7Bit_2:
+		return DataCodingGSM7, nil
+	case sms.Encodings.UCS2:
+		return DataCodingUCS2, nil
+	default:
+		return 0, ErrUnsupportedEncoding
+	}
+}
+
+func encodingFromDataCoding(dc byte) (sms.Encoding, error) {
+	switch dc {
+	case DataCodingGSM7:
+		return sms.Encodings.Gsm7Bit, nil
+	case DataCodingUCS2:
+		return sms.Encodings.UCS2, nil
+	default:
+		// DataCodingLatin1 falls through here too: sms.Message has no
+		// Latin-1 Encoding to map it to, and decoding it as GSM 7-bit
+		// would silently corrupt the text, so it's reported as
+		// unsupported rather than guessed at.
+		return 0, ErrUnsupportedEncoding
+	}
+}
+
+func registeredDelivery(requested bool) byte {
+	if requested {
+		return 0x01
+	}
+	return 0x00
+}
+
+func address(n sms.PhoneNumber) Address {
+	return Address{TON: ton(n.Type()), NPI: NPIISDN, Number: string(n)}
+}
+
+func ton(t sms.TypeOfAddress) byte {
+	switch t {
+	case sms.TypeOfAddresses.International:
+		return TONInternational
+	case sms.TypeOfAddresses.National:
+		return TONNational
+	case sms.TypeOfAddresses.Alphanumeric:
+		return TONAlphanumeric
+	default:
+		return TONUnknown
+	}
+}
+
+// validityPeriodString renders vp as an SMPP validity_period string: the
+// relative form "0000DDhhmmss000R" for ValidityPeriodFormats.Relative, or
+// the absolute form "YYMMDDhhmmsstnnp" for ValidityPeriodFormats.Absolute.
+// An Enhanced period is rendered using whichever of those two its active
+// sub-format resembles, falling back to "" (not present) otherwise.
+func validityPeriodString(vp sms.ValidityPeriod, format sms.ValidityPeriodFormat) string {
+	switch format {
+	case sms.ValidityPeriodFormats.Relative:
+		return relativeVPString(vp.Relative)
+	case sms.ValidityPeriodFormats.Absolute:
+		return absoluteVPString(vp.Absolute)
+	case sms.ValidityPeriodFormats.Enhanced:
+		switch vp.EnhancedFormat {
+		case 1:
+			return relativeVPString(vp.Relative)
+		case 2:
+			return relativeVPString(time.Duration(vp.Seconds) * time.Second)
+		case 3:
+			return relativeVPString(time.Duration(decSemiOctet(vp.HHMMSS[0]))*time.Hour +
+				time.Duration(decSemiOctet(vp.HHMMSS[1]))*time.Minute +
+				time.Duration(decSemiOctet(vp.HHMMSS[2]))*time.Second)
+		}
+	}
+	return ""
+}
+
+func relativeVPString(d time.Duration) string {
+	d = d.Round(time.Second)
+	// The relative time format has no calendar semantics: YY and MM stay
+	// 0, but DD must absorb whole days so hh stays within 0-23 - the GSM
+	// relative VP table already goes up to 63 weeks, well past a 2-digit
+	// hour field. DD is itself only 2 digits, so periods at or beyond 100
+	// days (the table goes up to 441) are clamped to the longest
+	// representable value rather than overflowing the fixed-width string.
+	totalHours := int(d.Hours())
+	days := totalHours / 24
+	h := totalHours % 24
+	m := int(d.Minutes()) % 60
+	s := int(d.Seconds()) % 60
+	if days > 99 {
+		days, h, m, s = 99, 23, 59, 59
+	}
+	return fmt.Sprintf("0000%02d%02d%02d%02d000R", days, h, m, s)
+}
+
+func absoluteVPString(t sms.Timestamp) string {
+	octets := t.PDU()
+	if len(octets) != 7 {
+		return ""
+	}
+	// The timezone octet holds quarter-hours from UTC, with bit 3 as sign.
+	sign := '+'
+	if octets[6]&0x08 != 0 {
+		sign = '-'
+	}
+	return fmt.Sprintf("%02d%02d%02d%02d%02d%02d0%02d%c",
+		decSemiOctet(octets[0]), decSemiOctet(octets[1]), decSemiOctet(octets[2]),
+		decSemiOctet(octets[3]), decSemiOctet(octets[4]), decSemiOctet(octets[5]),
+		decSemiOctet(octets[6]&^0x08), sign)
+}
+
+func decSemiOctet(b byte) int {
+	return int(b&0x0F)*10 + int(b>>4)
+}
+
+// concatIEs extracts a concatenation IE's reference, sequence number and
+// total segment count from udh, if present.
+func concatIEs(udh sms.UserDataHeader) (ref uint16, seq, total byte, ok bool) {
+	raw := udh.Bytes()
+	for i := 1; i+1 < len(raw); {
+		iei := raw[i]
+		ieLen := int(raw[i+1])
+		if i+2+ieLen > len(raw) {
+			return 0, 0, 0, false
+		}
+		data := raw[i+2 : i+2+ieLen]
+		switch iei {
+		case 0x00:
+			if len(data) == 3 {
+				return uint16(data[0]), data[2], data[1], true
+			}
+		case 0x08:
+			if len(data) == 4 {
+				return uint16(data[0])<<8 | uint16(data[1]), data[3], data[2], true
+			}
+		}
+		i += 2 + ieLen
+	}
+	return 0, 0, 0, false
+}
+
+func sarTLVs(tlvs []TLV) (ref uint16, total, seq byte, ok bool) {
+	var haveRef, haveTotal, haveSeq bool
+	for _, t := range tlvs {
+		switch t.Tag {
+		case TagSarMsgRefNum:
+			if len(t.Value) == 2 {
+				ref = uint16(t.Value[0])<<8 | uint16(t.Value[1])
+				haveRef = true
+			}
+		case TagSarTotalSegments:
+			if len(t.Value) == 1 {
+				total = t.Value[0]
+				haveTotal = true
+			}
+		case TagSarSegmentSeqnum:
+			if len(t.Value) == 1 {
+				seq = t.Value[0]
+				haveSeq = true
+			}
+		}
+	}
+	return ref, total, seq, haveRef && haveTotal && haveSeq
+}
+
+func concatHeaderBytes(ref uint16, total, seq byte) []byte {
+	return []byte{0x06, 0x08, 0x04, byte(ref >> 8), byte(ref), total, seq}
+}
+
+// packGsm7AfterHeader packs text's 7-bit septets so they start right after
+// headerLen octets' worth of fill bits, mirroring the alignment
+// sms.Message applies when a UDH precedes 7-bit text: plain byte
+// concatenation of a UDH and pdu.Encode7Bit's output leaves the text
+// septets bit-misaligned for any SMSC expecting a standards-compliant
+// inline UDH.
+func packGsm7AfterHeader(text string, headerLen int) []byte {
+	packed := pdu.Encode7Bit(text)
+	if headerLen == 0 {
+		return packed
+	}
+	headerSeptets := blocks(headerLen*8, 7)
+	fillBits := headerSeptets*7 - headerLen*8
+	septets := unpackSeptets(packed, len([]rune(text)))
+	return packSeptets(septets, fillBits)
+}
+
+func blocks(n, block int) int {
+	if n%block == 0 {
+		return n / block
+	}
+	return n/block + 1
+}
+
+// packSeptets packs septet values (0-0x7F each) into octets, LSB-first,
+// starting fillBits into the first octet so the stream lines up right
+// after the padding a preceding UDH leaves at the next septet boundary.
+func packSeptets(septets []byte, fillBits int) []byte {
+	var out []byte
+	var buf uint16
+	bits := uint(fillBits)
+	for _, sep := range septets {
+		buf |= uint16(sep&0x7F) << bits
+		bits += 7
+		if bits >= 8 {
+			out = append(out, byte(buf))
+			buf >>= 8
+			bits -= 8
+		}
+	}
+	if bits > 0 {
+		out = append(out, byte(buf))
+	}
+	return out
+}
+
+// unpackSeptets reverses the packing pdu.Encode7Bit produces (fillBits=0),
+// recovering the count septet values it encoded.
+func unpackSeptets(packed []byte, count int) []byte {
+	septets := make([]byte, 0, count)
+	var buf uint16
+	var bits uint
+	for _, b := range packed {
+		buf |= uint16(b) << bits
+		bits += 8
+		for bits >= 7 && len(septets) < count {
+			septets = append(septets, byte(buf&0x7F))
+			buf >>= 7
+			bits -= 7
+		}
+		if len(septets) >= count {
+			break
+		}
+	}
+	return septets
+}
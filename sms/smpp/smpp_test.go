@@ -0,0 +1,105 @@
+package smpp
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xlab/at/sms"
+)
+
+func TestToSubmitSMFromDeliverSMRoundTrip(t *testing.T) {
+	for _, enc := range []sms.Encoding{sms.Encodings.Gsm7Bit, sms.Encodings.UCS2} {
+		m := &sms.Message{
+			Type:     sms.MessageTypes.Submit,
+			Encoding: enc,
+			Address:  sms.PhoneNumber("15551234567"),
+			VPFormat: sms.ValidityPeriodFormats.Relative,
+			VP:       sms.ValidityPeriod{Relative: 30 * 60 * 1e9},
+			Text:     "hello world",
+		}
+
+		submit, err := ToSubmitSM(m)
+		if err != nil {
+			t.Fatalf("ToSubmitSM(%v): %v", enc, err)
+		}
+
+		deliver := &DeliverSM{
+			SourceAddr:   submit.DestAddr,
+			DataCoding:   submit.DataCoding,
+			EsmClass:     submit.EsmClass,
+			ShortMessage: append([]byte(nil), submit.ShortMessage...),
+		}
+		got, err := FromDeliverSM(deliver)
+		if err != nil {
+			t.Fatalf("FromDeliverSM(%v): %v", enc, err)
+		}
+		if got.Text != m.Text {
+			t.Errorf("%v: text round trip: got %q, want %q", enc, got.Text, m.Text)
+		}
+		if string(got.Address) != string(m.Address) {
+			t.Errorf("%v: address round trip: got %q, want %q", enc, got.Address, m.Address)
+		}
+	}
+}
+
+func TestToSubmitSMConcatenationCarriesSARTLVs(t *testing.T) {
+	m := &sms.Message{
+		Encoding: sms.Encodings.Gsm7Bit,
+		Text:     "part text",
+	}
+	if err := m.UserDataHeader.ReadFrom(concatHeaderBytes(0x1234, 3, 2)); err != nil {
+		t.Fatalf("UserDataHeader.ReadFrom: %v", err)
+	}
+	m.UserDataStartsWithHeader = true
+
+	submit, err := ToSubmitSM(m)
+	if err != nil {
+		t.Fatalf("ToSubmitSM: %v", err)
+	}
+	if len(submit.TLVs) != 3 {
+		t.Fatalf("got %d TLVs, want 3 (sar_msg_ref_num, sar_total_segments, sar_segment_seqnum)", len(submit.TLVs))
+	}
+	ref, total, seq, ok := sarTLVs(submit.TLVs)
+	if !ok {
+		t.Fatal("sarTLVs: not all three SAR TLVs present")
+	}
+	if ref != 0x1234 || total != 3 || seq != 2 {
+		t.Errorf("sarTLVs: got ref=%#x total=%d seq=%d, want ref=0x1234 total=3 seq=2", ref, total, seq)
+	}
+	if submit.EsmClass&esmClassUDHI != 0 {
+		t.Error("EsmClass: UDHI bit set, but concatenation info moved to SAR TLVs")
+	}
+}
+
+func TestFromDeliverSMReconstructsConcatUDHFromSARTLVs(t *testing.T) {
+	deliver := &DeliverSM{
+		DataCoding: DataCodingGSM7,
+		TLVs: []TLV{
+			{Tag: TagSarMsgRefNum, Value: []byte{0x01, 0x02}},
+			{Tag: TagSarTotalSegments, Value: []byte{3}},
+			{Tag: TagSarSegmentSeqnum, Value: []byte{1}},
+		},
+		ShortMessage: []byte("abc"),
+	}
+	m, err := FromDeliverSM(deliver)
+	if err != nil {
+		t.Fatalf("FromDeliverSM: %v", err)
+	}
+	if !m.UserDataStartsWithHeader {
+		t.Fatal("UserDataStartsWithHeader: got false, want true")
+	}
+	want := concatHeaderBytes(0x0102, 3, 1)
+	var wantUDH sms.UserDataHeader
+	if err := wantUDH.ReadFrom(want); err != nil {
+		t.Fatalf("ReadFrom(want): %v", err)
+	}
+	if !bytes.Equal(m.UserDataHeader.Bytes(), wantUDH.Bytes()) {
+		t.Errorf("UserDataHeader: got % x, want % x", m.UserDataHeader.Bytes(), wantUDH.Bytes())
+	}
+}
+
+func TestEncodingFromDataCodingRejectsLatin1(t *testing.T) {
+	if _, err := encodingFromDataCoding(DataCodingLatin1); err != ErrUnsupportedEncoding {
+		t.Errorf("encodingFromDataCoding(Latin1): got %v, want ErrUnsupportedEncoding", err)
+	}
+}
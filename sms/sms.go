@@ -14,7 +14,6 @@ var (
 	ErrUnknownEncoding               = errors.New("sms: unsupported encoding")
 	ErrUnknownMessageType            = errors.New("sms: unsupported message type")
 	ErrIncorrectSize                 = errors.New("sms: decoded incorrect size of field")
-	ErrNonRelative                   = errors.New("sms: non-relative validity period support is not implemented yet")
 	ErrIncorrectUserDataHeaderLength = errors.New("sms: incorrect user data header length ")
 )
 
@@ -61,21 +60,28 @@ func cutStr(str string, n int) string {
 	return str
 }
 
-// PDU serializes the message into octets ready to be transferred.
-// Returns the number of TPDU bytes in the produced PDU.
+// AppendPDU serializes the message and appends the resulting octets to
+// dst, in the style of strconv.AppendInt. It returns the extended buffer
+// along with the number of TPDU bytes appended (not counting the leading
+// SMSC info length/address). Passing a dst with spare capacity avoids
+// allocating a fresh output buffer on every call, which matters when
+// submitting many segments back to back, e.g. the ones produced by Split.
+// The TPDU fields are still assembled in intermediate slices (address
+// octets, user data, and the Bytes() output of the TPDU struct) before
+// being appended to dst.
 // Complies with 3GPP TS 23.040.
-func (s *Message) PDU() (int, []byte, error) {
-	var buf bytes.Buffer
+func (s *Message) AppendPDU(dst []byte) ([]byte, int, error) {
 	if len(s.ServiceCenterAddress) < 1 {
-		buf.WriteByte(0x00) // SMSC info length
+		dst = append(dst, 0x00) // SMSC info length
 	} else {
 		_, octets, err := s.ServiceCenterAddress.PDU()
 		if err != nil {
-			return 0, nil, err
+			return dst, 0, err
 		}
-		buf.WriteByte(byte(len(octets)))
-		buf.Write(octets)
+		dst = append(dst, byte(len(octets)))
+		dst = append(dst, octets...)
 	}
+	tpduStart := len(dst)
 
 	switch s.Type {
 	case MessageTypes.Deliver:
@@ -89,35 +95,22 @@ func (s *Message) PDU() (int, []byte, error) {
 
 		addrLen, addr, err := s.Address.PDU()
 		if err != nil {
-			return 0, nil, err
+			return dst, 0, err
 		}
-		var addrBuf bytes.Buffer
-		addrBuf.WriteByte(byte(addrLen))
-		addrBuf.Write(addr)
-		sms.OriginatingAddress = addrBuf.Bytes()
+		sms.OriginatingAddress = append([]byte{byte(addrLen)}, addr...)
 
 		sms.ProtocolIdentifier = 0x00 // Short Message Type 0
 		sms.DataCodingScheme = byte(s.Encoding)
 		sms.ServiceCentreTimestamp = s.ServiceCenterTime.PDU()
 
-		var userData []byte
-		switch s.Encoding {
-		case Encodings.Gsm7Bit, Encodings.Gsm7Bit_2:
-			userData = pdu.Encode7Bit(s.Text)
-			sms.UserDataLength = byte(len(s.Text))
-		case Encodings.UCS2:
-			userData = pdu.EncodeUcs2(s.Text)
-			sms.UserDataLength = byte(len(userData))
-		default:
-			return 0, nil, ErrUnknownEncoding
-		}
-
-		sms.UserData = userData
-		n, err := buf.Write(sms.Bytes())
+		userData, udLength, err := encodeUserData(s)
 		if err != nil {
-			return 0, nil, err
+			return dst, 0, err
 		}
-		return n, buf.Bytes(), nil
+		sms.UserData = userData
+		sms.UserDataLength = udLength
+
+		dst = append(dst, sms.Bytes()...)
 	case MessageTypes.Submit:
 		var sms smsSubmit
 		sms.MessageTypeIndicator = byte(s.Type)
@@ -130,41 +123,30 @@ func (s *Message) PDU() (int, []byte, error) {
 
 		addrLen, addr, err := s.Address.PDU()
 		if err != nil {
-			return 0, nil, err
+			return dst, 0, err
 		}
-		var addrBuf bytes.Buffer
-		addrBuf.WriteByte(byte(addrLen))
-		addrBuf.Write(addr)
-		sms.DestinationAddress = addrBuf.Bytes()
+		sms.DestinationAddress = append([]byte{byte(addrLen)}, addr...)
 
 		sms.ProtocolIdentifier = 0x00 // Short Message Type 0
 		sms.DataCodingScheme = byte(s.Encoding)
 
 		switch s.VPFormat {
 		case ValidityPeriodFormats.Relative:
-			sms.ValidityPeriod = byte(s.VP.Octet())
-		case ValidityPeriodFormats.Absolute, ValidityPeriodFormats.Enhanced:
-			return 0, nil, ErrNonRelative
+			sms.ValidityPeriod = []byte{s.VP.Octet()}
+		case ValidityPeriodFormats.Absolute:
+			sms.ValidityPeriod = s.VP.AbsoluteBytes()
+		case ValidityPeriodFormats.Enhanced:
+			sms.ValidityPeriod = s.VP.EnhancedBytes()
 		}
 
-		var userData []byte
-		switch s.Encoding {
-		case Encodings.Gsm7Bit, Encodings.Gsm7Bit_2:
-			userData = pdu.Encode7Bit(s.Text)
-			sms.UserDataLength = byte(len(s.Text))
-		case Encodings.UCS2:
-			userData = pdu.EncodeUcs2(s.Text)
-			sms.UserDataLength = byte(len(userData))
-		default:
-			return 0, nil, ErrUnknownEncoding
-		}
-
-		sms.UserData = userData
-		n, err := buf.Write(sms.Bytes())
+		userData, udLength, err := encodeUserData(s)
 		if err != nil {
-			return 0, nil, err
+			return dst, 0, err
 		}
-		return n, buf.Bytes(), nil
+		sms.UserData = userData
+		sms.UserDataLength = udLength
+
+		dst = append(dst, sms.Bytes()...)
 	case MessageTypes.StatusReport:
 		var sms smsStatusReport
 		sms.MessageTypeIndicator = byte(s.Type)
@@ -176,38 +158,125 @@ func (s *Message) PDU() (int, []byte, error) {
 
 		addrLen, addr, err := s.Address.PDU()
 		if err != nil {
-			return 0, nil, err
+			return dst, 0, err
 		}
-		var addrBuf bytes.Buffer
-		addrBuf.WriteByte(byte(addrLen))
-		addrBuf.Write(addr)
-		sms.DestinationAddress = addrBuf.Bytes()
+		sms.DestinationAddress = append([]byte{byte(addrLen)}, addr...)
 
 		sms.ServiceCentreTimestamp = s.ServiceCenterTime.PDU()
 		sms.DischargeTimestamp = s.DischargeTime.PDU()
 		sms.Status = s.Status
 
-		var userData []byte
-		switch s.Encoding {
-		case Encodings.Gsm7Bit, Encodings.Gsm7Bit_2:
-			userData = pdu.Encode7Bit(s.Text)
-			sms.UserDataLength = byte(len(s.Text))
-		case Encodings.UCS2:
-			userData = pdu.EncodeUcs2(s.Text)
-			sms.UserDataLength = byte(len(userData))
-		default:
-			return 0, nil, ErrUnknownEncoding
+		userData, udLength, err := encodeUserData(s)
+		if err != nil {
+			return dst, 0, err
 		}
-
 		sms.UserData = userData
-		n, err := buf.Write(sms.Bytes())
-		if err != nil {
-			return 0, nil, err
+		sms.UserDataLength = udLength
+
+		dst = append(dst, sms.Bytes()...)
+	default:
+		return dst, 0, ErrUnknownMessageType
+	}
+	return dst, len(dst) - tpduStart, nil
+}
+
+// PDU serializes the message into octets ready to be transferred.
+// Returns the number of TPDU bytes in the produced PDU.
+// Complies with 3GPP TS 23.040.
+func (s *Message) PDU() (int, []byte, error) {
+	buf, n, err := s.AppendPDU(nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	return n, buf, nil
+}
+
+// WriteTo writes the message's PDU encoding to w, satisfying io.WriterTo
+// symmetrically with ReadFrom.
+func (s *Message) WriteTo(w io.Writer) (int64, error) {
+	buf, _, err := s.AppendPDU(nil)
+	if err != nil {
+		return 0, err
+	}
+	n, err := w.Write(buf)
+	return int64(n), err
+}
+
+// encodeUserData encodes s.Text (and, if present, s.UserDataHeader) into
+// the TP-User-Data octets and the TP-User-Data-Length value shared by the
+// Deliver, Submit and StatusReport encodings.
+func encodeUserData(s *Message) (userData []byte, udLength byte, err error) {
+	var header []byte
+	if s.UserDataStartsWithHeader {
+		header = s.UserDataHeader.Bytes()
+	}
+	switch s.Encoding {
+	case Encodings.Gsm7Bit, Encodings.Gsm7Bit_2:
+		text := pdu.Encode7Bit(s.Text)
+		if len(header) == 0 {
+			return text, byte(len(s.Text)), nil
 		}
-		return n, buf.Bytes(), nil
+		// TP-UDL is counted in septets, and a UDH counts towards it too:
+		// it occupies ceil(len(header)*8/7) septets once padded to the
+		// next septet boundary. The text septets are then re-packed to
+		// start right after that boundary, rather than sharing an octet
+		// with the header.
+		headerSeptets := blocks(len(header)*8, 7)
+		fillBits := headerSeptets*7 - len(header)*8
+		septets := unpackSeptets(text, len(s.Text))
+		packedText := packSeptets(septets, fillBits)
+		userData = append(append([]byte{}, header...), packedText...)
+		return userData, byte(headerSeptets + len(s.Text)), nil
+	case Encodings.UCS2:
+		text := pdu.EncodeUcs2(s.Text)
+		userData = append(header, text...)
+		return userData, byte(len(userData)), nil
 	default:
-		return 0, nil, ErrUnknownMessageType
+		return nil, 0, ErrUnknownEncoding
+	}
+}
+
+// packSeptets packs septet values (0-0x7F each) into octets, LSB-first,
+// starting fillBits into the first octet so the stream lines up right
+// after the padding a preceding UDH leaves at the next septet boundary.
+func packSeptets(septets []byte, fillBits int) []byte {
+	var out []byte
+	var buf uint16
+	bits := uint(fillBits)
+	for _, sep := range septets {
+		buf |= uint16(sep&0x7F) << bits
+		bits += 7
+		if bits >= 8 {
+			out = append(out, byte(buf))
+			buf >>= 8
+			bits -= 8
+		}
+	}
+	if bits > 0 {
+		out = append(out, byte(buf))
+	}
+	return out
+}
+
+// unpackSeptets reverses the packing pdu.Encode7Bit produces (fillBits=0),
+// recovering the count septet values it encoded.
+func unpackSeptets(packed []byte, count int) []byte {
+	septets := make([]byte, 0, count)
+	var buf uint16
+	var bits uint
+	for _, b := range packed {
+		buf |= uint16(b) << bits
+		bits += 8
+		for bits >= 7 && len(septets) < count {
+			septets = append(septets, byte(buf&0x7F))
+			buf >>= 7
+			bits -= 7
+		}
+		if len(septets) >= count {
+			break
+		}
 	}
+	return septets
 }
 
 // ReadFrom constructs a message from the supplied PDU octets. Returns the number of bytes read.
@@ -284,13 +353,7 @@ func (s *Message) ReadFrom(octets []byte) (n int, err error) {
 			return n, err2
 		}
 		s.RejectDuplicates = sms.RejectDuplicates
-
-		switch s.VPFormat {
-		case ValidityPeriodFormats.Absolute, ValidityPeriodFormats.Enhanced:
-			return n, ErrNonRelative
-		default:
-			s.VPFormat = ValidityPeriodFormat(sms.ValidityPeriodFormat)
-		}
+		s.VPFormat = ValidityPeriodFormat(sms.ValidityPeriodFormat)
 
 		s.MessageReference = sms.MessageReference
 		s.ReplyPathExists = sms.ReplyPath
@@ -299,8 +362,20 @@ func (s *Message) ReadFrom(octets []byte) (n int, err error) {
 		s.Address.ReadFrom(sms.DestinationAddress[1:])
 		s.Encoding = Encoding(sms.DataCodingScheme)
 
-		if s.VPFormat != ValidityPeriodFormats.FieldNotPresent {
-			s.VP.ReadFrom(sms.ValidityPeriod)
+		switch s.VPFormat {
+		case ValidityPeriodFormats.Relative:
+			if len(sms.ValidityPeriod) != 1 {
+				return n, ErrIncorrectSize
+			}
+			s.VP.ReadFrom(sms.ValidityPeriod[0])
+		case ValidityPeriodFormats.Absolute:
+			if err = s.VP.ReadAbsoluteFrom(sms.ValidityPeriod); err != nil {
+				return n, err
+			}
+		case ValidityPeriodFormats.Enhanced:
+			if err = s.VP.ReadEnhancedFrom(sms.ValidityPeriod); err != nil {
+				return n, err
+			}
 		}
 
 		switch s.Encoding {
@@ -0,0 +1,77 @@
+package sms
+
+import (
+	"strings"
+
+	"github.com/xlab/at/pdu"
+)
+
+// Ussd represents a USSD string as exchanged via AT+CUSD, mirroring how
+// Message encodes and decodes its Text with pdu.Encode7Bit/pdu.EncodeUcs2
+// and pdu.Decode7Bit/pdu.DecodeUcs2.
+type Ussd string
+
+// gsm7BitExtChars are the characters encoded via the GSM 03.38 extension
+// table (escape sequence 0x1B + a second septet), so each costs 2 septets
+// instead of 1.
+var gsm7BitExtChars = map[rune]bool{
+	'^': true, '{': true, '}': true, '\\': true, '[': true, ']': true,
+	'~': true, '|': true, '\f': true, '€': true,
+}
+
+// septetCount returns the number of GSM 7-bit septets text would pack
+// into, counting extension-table characters twice.
+func septetCount(text string) int {
+	n := 0
+	for _, r := range text {
+		n++
+		if gsm7BitExtChars[r] {
+			n++
+		}
+	}
+	return n
+}
+
+// Encode serializes u using enc, returning the encoded octets and the DCS
+// byte a caller should send alongside them in AT+CUSD. The GSM 7-bit path
+// follows the CBS (cell broadcast) packing convention used for USSD
+// responses: a trailing <CR> is appended before packing whenever the text
+// would otherwise leave 7 spare bits in its last octet, so a decoder does
+// not read those zero bits as a trailing '@' septet.
+func (u Ussd) Encode(enc Encoding) ([]byte, []byte, error) {
+	switch enc {
+	case Encodings.Gsm7Bit, Encodings.Gsm7Bit_2:
+		text := string(u)
+		if septetCount(text)%8 == 7 {
+			text += "\r"
+		}
+		return pdu.Encode7Bit(text), []byte{byte(enc)}, nil
+	case Encodings.UCS2:
+		return pdu.EncodeUcs2(string(u)), []byte{byte(enc)}, nil
+	default:
+		return nil, nil, ErrUnknownEncoding
+	}
+}
+
+// DecodeUssd parses octets, encoded as advertised by the network in dcs,
+// back into a Ussd string. A trailing CBS <CR> padding octet added by
+// Encode, if any, is trimmed.
+func DecodeUssd(octets []byte, dcs byte) (Ussd, error) {
+	switch enc := Encoding(dcs); enc {
+	case Encodings.Gsm7Bit, Encodings.Gsm7Bit_2:
+		text, err := pdu.Decode7Bit(octets)
+		if err != nil {
+			return "", err
+		}
+		text = strings.TrimSuffix(text, "\r")
+		return Ussd(text), nil
+	case Encodings.UCS2:
+		text, err := pdu.DecodeUcs2(octets, false)
+		if err != nil {
+			return "", err
+		}
+		return Ussd(text), nil
+	default:
+		return "", ErrUnknownEncoding
+	}
+}
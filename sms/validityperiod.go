@@ -0,0 +1,142 @@
+package sms
+
+import "time"
+
+// ValidityPeriod represents the TP-Validity-Period field, as described in
+// 3GPP TS 23.040 §9.2.3.12. Only the fields relevant to the active
+// ValidityPeriodFormat are populated: Relative for the Relative format,
+// Absolute for the Absolute format, and EnhancedFormat together with
+// Relative, Seconds or HHMMSS for the Enhanced format.
+type ValidityPeriod struct {
+	// Relative holds the period for ValidityPeriodFormats.Relative, and
+	// also backs Enhanced sub-format 1 (single-octet relative).
+	Relative time.Duration
+
+	// Absolute holds the period for ValidityPeriodFormats.Absolute: a
+	// 7-octet semi-octet timestamp, identical in layout to Timestamp.
+	Absolute Timestamp
+
+	// EnhancedFormat selects the Enhanced sub-format (octet 1 bits 0-2):
+	// 0 = no VP, 1 = single-octet relative (see Relative), 2 = integer
+	// seconds (see Seconds), 3 = semi-octet HH:MM:SS (see HHMMSS).
+	EnhancedFormat byte
+
+	// Seconds holds the period in seconds for Enhanced sub-format 2.
+	Seconds uint32
+
+	// HHMMSS holds the period as semi-octet hours/minutes/seconds for
+	// Enhanced sub-format 3.
+	HHMMSS [3]byte
+
+	// SingleShot reflects the "single shot SM" bit (octet 1 bit 6) of the
+	// Enhanced VP field.
+	SingleShot bool
+}
+
+// Octet encodes Relative as the single TP-VP octet used by
+// ValidityPeriodFormats.Relative, per 3GPP TS 23.040 §9.2.3.12.1.
+func (vp ValidityPeriod) Octet() byte {
+	return relativeOctet(vp.Relative)
+}
+
+// ReadFrom decodes a single relative TP-VP octet into vp.Relative.
+func (vp *ValidityPeriod) ReadFrom(octet byte) {
+	vp.Relative = relativeDuration(octet)
+}
+
+// AbsoluteBytes encodes vp.Absolute as the 7-octet semi-octet timestamp
+// used by ValidityPeriodFormats.Absolute, per 3GPP TS 23.040 §9.2.3.12.2.
+func (vp ValidityPeriod) AbsoluteBytes() []byte {
+	return vp.Absolute.PDU()
+}
+
+// ReadAbsoluteFrom decodes a 7-octet semi-octet timestamp into vp.Absolute.
+func (vp *ValidityPeriod) ReadAbsoluteFrom(octets []byte) error {
+	if len(octets) != 7 {
+		return ErrIncorrectSize
+	}
+	vp.Absolute.ReadFrom(octets)
+	return nil
+}
+
+// EnhancedBytes encodes vp as the 7-octet field used by
+// ValidityPeriodFormats.Enhanced, per 3GPP TS 23.040 §9.2.3.12.3.
+func (vp ValidityPeriod) EnhancedBytes() []byte {
+	octets := make([]byte, 7)
+	octets[0] = vp.EnhancedFormat & 0x07
+	if vp.SingleShot {
+		octets[0] |= 0x40
+	}
+	switch vp.EnhancedFormat & 0x07 {
+	case 1:
+		octets[1] = vp.Octet()
+	case 2:
+		octets[1] = byte(vp.Seconds >> 16)
+		octets[2] = byte(vp.Seconds >> 8)
+		octets[3] = byte(vp.Seconds)
+	case 3:
+		copy(octets[1:4], vp.HHMMSS[:])
+	}
+	return octets
+}
+
+// ReadEnhancedFrom decodes a 7-octet Enhanced VP field into vp.
+func (vp *ValidityPeriod) ReadEnhancedFrom(octets []byte) error {
+	if len(octets) != 7 {
+		return ErrIncorrectSize
+	}
+	vp.EnhancedFormat = octets[0] & 0x07
+	vp.SingleShot = octets[0]&0x40 != 0
+	switch vp.EnhancedFormat {
+	case 1:
+		vp.ReadFrom(octets[1])
+	case 2:
+		vp.Seconds = uint32(octets[1])<<16 | uint32(octets[2])<<8 | uint32(octets[3])
+	case 3:
+		copy(vp.HHMMSS[:], octets[1:4])
+	}
+	return nil
+}
+
+// relativeOctet and relativeDuration implement the relative VP table from
+// 3GPP TS 23.040 §9.2.3.12.1.
+func relativeOctet(d time.Duration) byte {
+	switch {
+	case d <= 12*time.Hour:
+		min := d / (5 * time.Minute)
+		if min < 1 {
+			min = 1
+		}
+		return byte(min - 1)
+	case d <= 24*time.Hour:
+		return byte(143 + (d-12*time.Hour)/(30*time.Minute))
+	case d <= 30*24*time.Hour:
+		days := d / (24 * time.Hour)
+		if days < 2 {
+			days = 2
+		}
+		return byte(166 + days)
+	default:
+		weeks := d / (7 * 24 * time.Hour)
+		if weeks < 5 {
+			weeks = 5
+		}
+		if weeks > 63 {
+			weeks = 63
+		}
+		return byte(192 + weeks)
+	}
+}
+
+func relativeDuration(octet byte) time.Duration {
+	switch {
+	case octet <= 143:
+		return time.Duration(octet+1) * 5 * time.Minute
+	case octet <= 167:
+		return 12*time.Hour + time.Duration(octet-143)*30*time.Minute
+	case octet <= 196:
+		return time.Duration(octet-166) * 24 * time.Hour
+	default:
+		return time.Duration(octet-192) * 7 * 24 * time.Hour
+	}
+}
@@ -0,0 +1,91 @@
+package sms
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidityPeriodRelativeRoundTrip(t *testing.T) {
+	cases := []time.Duration{
+		5 * time.Minute,
+		30 * time.Minute,
+		12 * time.Hour,
+		18 * time.Hour,
+		2 * 24 * time.Hour,
+		29 * 24 * time.Hour,
+		5 * 7 * 24 * time.Hour,
+		63 * 7 * 24 * time.Hour,
+	}
+	for _, d := range cases {
+		vp := ValidityPeriod{Relative: d}
+		octet := vp.Octet()
+
+		var got ValidityPeriod
+		got.ReadFrom(octet)
+		if got.Relative != d {
+			t.Errorf("relative %v: round trip via octet 0x%02x gave %v", d, octet, got.Relative)
+		}
+	}
+}
+
+func TestValidityPeriodAbsoluteRoundTrip(t *testing.T) {
+	want := Timestamp{}
+	vp := ValidityPeriod{Absolute: want}
+	octets := vp.AbsoluteBytes()
+	if len(octets) != 7 {
+		t.Fatalf("AbsoluteBytes returned %d octets, want 7", len(octets))
+	}
+
+	var got ValidityPeriod
+	if err := got.ReadAbsoluteFrom(octets); err != nil {
+		t.Fatalf("ReadAbsoluteFrom: %v", err)
+	}
+	if got.Absolute != want {
+		t.Errorf("absolute round trip: got %+v, want %+v", got.Absolute, want)
+	}
+
+	if err := got.ReadAbsoluteFrom(octets[:6]); err == nil {
+		t.Error("ReadAbsoluteFrom with 6 octets: want error, got nil")
+	}
+}
+
+func TestValidityPeriodEnhancedRoundTrip(t *testing.T) {
+	cases := []ValidityPeriod{
+		{EnhancedFormat: 1, Relative: 30 * time.Minute},
+		{EnhancedFormat: 2, Seconds: 1<<24 - 1},
+		{EnhancedFormat: 3, HHMMSS: [3]byte{0x01, 0x02, 0x03}},
+		{EnhancedFormat: 1, Relative: time.Hour, SingleShot: true},
+	}
+	for _, vp := range cases {
+		octets := vp.EnhancedBytes()
+		if len(octets) != 7 {
+			t.Fatalf("EnhancedBytes returned %d octets, want 7", len(octets))
+		}
+
+		var got ValidityPeriod
+		if err := got.ReadEnhancedFrom(octets); err != nil {
+			t.Fatalf("ReadEnhancedFrom: %v", err)
+		}
+		if got.EnhancedFormat != vp.EnhancedFormat || got.SingleShot != vp.SingleShot {
+			t.Errorf("format/single-shot: got %+v, want %+v", got, vp)
+		}
+		switch vp.EnhancedFormat {
+		case 1:
+			if got.Relative != vp.Relative {
+				t.Errorf("relative sub-format: got %v, want %v", got.Relative, vp.Relative)
+			}
+		case 2:
+			if got.Seconds != vp.Seconds {
+				t.Errorf("seconds sub-format: got %d, want %d", got.Seconds, vp.Seconds)
+			}
+		case 3:
+			if got.HHMMSS != vp.HHMMSS {
+				t.Errorf("HHMMSS sub-format: got %v, want %v", got.HHMMSS, vp.HHMMSS)
+			}
+		}
+
+		if err := got.ReadEnhancedFrom(octets[:6]); err == nil {
+			t.Error("ReadEnhancedFrom with 6 octets: want error, got nil")
+		}
+	}
+}